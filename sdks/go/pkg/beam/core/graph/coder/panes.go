@@ -0,0 +1,225 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+// The first byte of an encoded PaneInfo packs IsFirst, IsLast, Timing, and
+// the number of trailing varints together, matching the layout used by the
+// Java and Python SDKs:
+//
+//	bit 0:    IsFirst
+//	bit 1:    IsLast
+//	bits 2-3: Timing (Early=0, OnTime=1, Late=2, Unknown=3)
+//	bits 4-5: number of trailing varints (0, 1 or 2)
+//
+// A PaneUnknown pane is the synthetic "no firing" default: it carries no
+// index information, so it is encoded as a single byte with Index and
+// NonSpeculativeIndex implied to be 0. A PaneEarly pane is never
+// non-speculative, so only Index is varint-encoded and
+// NonSpeculativeIndex is implied to be -1. PaneOnTime and PaneLate panes
+// varint-encode both Index and NonSpeculativeIndex.
+const (
+	paneInfoFirstBit     = 0x01
+	paneInfoLastBit      = 0x02
+	paneInfoTimingShift  = 2
+	paneInfoTimingMask   = 0x03
+	paneInfoVarintsShift = 4
+	paneInfoVarintsMask  = 0x03
+
+	paneInfoNoVarints   = 0 // PaneUnknown: no trailing data.
+	paneInfoIndexOnly   = 1 // PaneEarly: Index only.
+	paneInfoBothIndices = 2 // PaneOnTime, PaneLate: Index and NonSpeculativeIndex.
+
+	// MaxPaneEncodedSize is the largest number of bytes a PaneInfo can
+	// ever encode to: one descriptor byte plus two int64 varints. Callers
+	// that want to avoid allocations entirely can size a scratch buffer
+	// with this constant and pass it to AppendPane.
+	MaxPaneEncodedSize = 1 + 2*binary.MaxVarintLen64
+)
+
+func paneInfoVarintCount(timing typex.PaneTiming) byte {
+	switch timing {
+	case typex.PaneUnknown:
+		return paneInfoNoVarints
+	case typex.PaneEarly:
+		return paneInfoIndexOnly
+	default:
+		return paneInfoBothIndices
+	}
+}
+
+func validatePane(pn typex.PaneInfo) error {
+	if pn.Timing == typex.PaneEarly && pn.NonSpeculativeIndex != -1 {
+		return errors.Errorf("invalid pane %v: early panes must have a NonSpeculativeIndex of -1, got %v", pn, pn.NonSpeculativeIndex)
+	}
+	if pn.Timing == typex.PaneUnknown && (pn.Index != 0 || pn.NonSpeculativeIndex != 0) {
+		return errors.Errorf("invalid pane %v: unknown-timing panes must have Index and NonSpeculativeIndex of 0", pn)
+	}
+	return nil
+}
+
+// AppendPane appends the encoded form of pn to dst and returns the extended
+// buffer. It is the allocation-free core of the pane encoding: encoding a
+// valid pane allocates only when dst lacks room for it, so a caller that
+// reuses dst across calls (e.g. a dst sized to MaxPaneEncodedSize) can
+// encode a PaneInfo with zero allocations. EncodePane is a thin io.Writer wrapper over this function.
+func AppendPane(dst []byte, pn typex.PaneInfo) ([]byte, error) {
+	if err := validatePane(pn); err != nil {
+		return nil, err
+	}
+
+	vc := paneInfoVarintCount(pn.Timing)
+	b := byte(pn.Timing&paneInfoTimingMask) << paneInfoTimingShift
+	if pn.IsFirst {
+		b |= paneInfoFirstBit
+	}
+	if pn.IsLast {
+		b |= paneInfoLastBit
+	}
+	b |= vc << paneInfoVarintsShift
+	dst = append(dst, b)
+
+	if vc >= paneInfoIndexOnly {
+		dst = binary.AppendVarint(dst, pn.Index)
+	}
+	if vc >= paneInfoBothIndices {
+		dst = binary.AppendVarint(dst, pn.NonSpeculativeIndex)
+	}
+	return dst, nil
+}
+
+// EncodePane encodes a typex.PaneInfo to w. See AppendPane for the exact
+// wire layout and for an allocation-free alternative.
+func EncodePane(pn typex.PaneInfo, w io.Writer) error {
+	var scratch [MaxPaneEncodedSize]byte
+	enc, err := AppendPane(scratch[:0], pn)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(enc); err != nil {
+		return errors.Wrapf(err, "error encoding pane %v", pn)
+	}
+	return nil
+}
+
+// ConsumePane decodes a typex.PaneInfo encoded by AppendPane or EncodePane
+// from the front of src, returning the decoded PaneInfo and the number of
+// bytes of src it consumed. It is the allocation-free core of pane
+// decoding. DecodePane decodes the same format from an io.Reader with its
+// own byte-by-byte reads; it does not call this function.
+func ConsumePane(src []byte) (typex.PaneInfo, int, error) {
+	if len(src) == 0 {
+		return typex.PaneInfo{}, 0, errors.New("error decoding pane descriptor byte: empty buffer")
+	}
+	b := src[0]
+	timing := typex.PaneTiming((b >> paneInfoTimingShift) & paneInfoTimingMask)
+	isFirst := b&paneInfoFirstBit != 0
+	isLast := b&paneInfoLastBit != 0
+	vc := (b >> paneInfoVarintsShift) & paneInfoVarintsMask
+
+	pos := 1
+	var index, nsIndex int64
+	if timing == typex.PaneEarly {
+		nsIndex = -1
+	}
+	if vc >= paneInfoIndexOnly {
+		v, n, err := consumeVarint(src[pos:])
+		if err != nil {
+			return typex.PaneInfo{}, 0, errors.Wrap(err, "error decoding pane index")
+		}
+		index, pos = v, pos+n
+	}
+	if vc >= paneInfoBothIndices {
+		v, n, err := consumeVarint(src[pos:])
+		if err != nil {
+			return typex.PaneInfo{}, 0, errors.Wrap(err, "error decoding pane non-speculative index")
+		}
+		nsIndex, pos = v, pos+n
+	}
+
+	return typex.PaneInfo{
+		Timing:              timing,
+		IsFirst:             isFirst,
+		IsLast:              isLast,
+		Index:               index,
+		NonSpeculativeIndex: nsIndex,
+	}, pos, nil
+}
+
+func consumeVarint(src []byte) (int64, int, error) {
+	v, n := binary.Varint(src)
+	if n == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if n < 0 {
+		return 0, 0, errors.New("varint overflows a 64-bit integer")
+	}
+	return v, n, nil
+}
+
+// DecodePane decodes a typex.PaneInfo encoded by EncodePane from r. See
+// ConsumePane for an allocation-free alternative over an existing byte
+// slice.
+func DecodePane(r io.Reader) (typex.PaneInfo, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	b, err := br.ReadByte()
+	if err != nil {
+		return typex.PaneInfo{}, errors.Wrap(err, "error decoding pane descriptor byte")
+	}
+	timing := typex.PaneTiming((b >> paneInfoTimingShift) & paneInfoTimingMask)
+	isFirst := b&paneInfoFirstBit != 0
+	isLast := b&paneInfoLastBit != 0
+	vc := (b >> paneInfoVarintsShift) & paneInfoVarintsMask
+
+	var index, nsIndex int64
+	if timing == typex.PaneEarly {
+		nsIndex = -1
+	}
+	if vc >= paneInfoIndexOnly {
+		v, err := binary.ReadVarint(br)
+		if err != nil {
+			return typex.PaneInfo{}, errors.Wrap(err, "error decoding pane index")
+		}
+		index = v
+	}
+	if vc >= paneInfoBothIndices {
+		v, err := binary.ReadVarint(br)
+		if err != nil {
+			return typex.PaneInfo{}, errors.Wrap(err, "error decoding pane non-speculative index")
+		}
+		nsIndex = v
+	}
+
+	return typex.PaneInfo{
+		Timing:              timing,
+		IsFirst:             isFirst,
+		IsLast:              isLast,
+		Index:               index,
+		NonSpeculativeIndex: nsIndex,
+	}, nil
+}
@@ -17,7 +17,13 @@ package coder
 
 import (
 	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"math"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
@@ -40,6 +46,7 @@ func TestPaneCoder(t *testing.T) {
 		index     int64
 		nsIndex   int64
 		firstByte byte
+		wire      []byte
 	}{
 		{
 			"false bools",
@@ -49,6 +56,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			0,
 			0b00001100,
+			[]byte{0x0c},
 		},
 		{
 			"true bools",
@@ -58,6 +66,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			0,
 			0b00001111,
+			[]byte{0x0f},
 		},
 		{
 			"first pane",
@@ -67,6 +76,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			0,
 			0b00001101,
+			[]byte{0x0d},
 		},
 		{
 			"last pane",
@@ -76,6 +86,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			0,
 			0b00001110,
+			[]byte{0x0e},
 		},
 		{
 			"on time, different index and non-speculative",
@@ -85,6 +96,7 @@ func TestPaneCoder(t *testing.T) {
 			1,
 			2,
 			0b00100100,
+			[]byte{0x24, 0x02, 0x04},
 		},
 		{
 			"valid early pane",
@@ -94,6 +106,7 @@ func TestPaneCoder(t *testing.T) {
 			math.MaxInt64,
 			-1,
 			0b00010001,
+			[]byte{0x11, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
 		},
 		{
 			"on time, max non-speculative index",
@@ -103,6 +116,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			math.MaxInt64,
 			0b00100110,
+			[]byte{0x26, 0x00, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
 		},
 		{
 			"late pane, max index",
@@ -112,6 +126,7 @@ func TestPaneCoder(t *testing.T) {
 			math.MaxInt64,
 			0,
 			0b00101000,
+			[]byte{0x28, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00},
 		},
 		{
 			"on time, min non-speculative index",
@@ -121,6 +136,7 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			math.MinInt64,
 			0b00100110,
+			[]byte{0x26, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
 		},
 		{
 			"late, min index",
@@ -130,6 +146,7 @@ func TestPaneCoder(t *testing.T) {
 			math.MinInt64,
 			0,
 			0b00101000,
+			[]byte{0x28, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00},
 		},
 		{
 			"last late firing",
@@ -139,6 +156,7 @@ func TestPaneCoder(t *testing.T) {
 			2,
 			1,
 			0b00101010,
+			[]byte{0x2a, 0x04, 0x02},
 		},
 		{
 			"encodeByte 41",
@@ -148,6 +166,7 @@ func TestPaneCoder(t *testing.T) {
 			2,
 			1,
 			0b00101001, // 41
+			[]byte{0x29, 0x04, 0x02},
 		},
 		{
 			"encodeByte 18",
@@ -157,6 +176,107 @@ func TestPaneCoder(t *testing.T) {
 			0,
 			-1,
 			0b00010010, // 18
+			[]byte{0x12, 0x00},
+		},
+		{
+			"index 63/64 varint boundary, low side",
+			typex.PaneOnTime,
+			false,
+			false,
+			63,
+			0,
+			0b00100100,
+			[]byte{0x24, 0x7e, 0x00},
+		},
+		{
+			"index 63/64 varint boundary, high side",
+			typex.PaneOnTime,
+			false,
+			false,
+			64,
+			0,
+			0b00100100,
+			[]byte{0x24, 0x80, 0x01, 0x00},
+		},
+		{
+			"non-speculative index 63/64 varint boundary, low side",
+			typex.PaneOnTime,
+			false,
+			false,
+			0,
+			63,
+			0b00100100,
+			[]byte{0x24, 0x00, 0x7e},
+		},
+		{
+			"non-speculative index 63/64 varint boundary, high side",
+			typex.PaneOnTime,
+			false,
+			false,
+			0,
+			64,
+			0b00100100,
+			[]byte{0x24, 0x00, 0x80, 0x01},
+		},
+		{
+			"index 8191/8192 varint boundary, low side",
+			typex.PaneLate,
+			true,
+			true,
+			8191,
+			0,
+			0b00101011,
+			[]byte{0x2b, 0xfe, 0x7f, 0x00},
+		},
+		{
+			"index 8191/8192 varint boundary, high side",
+			typex.PaneLate,
+			true,
+			true,
+			8192,
+			0,
+			0b00101011,
+			[]byte{0x2b, 0x80, 0x80, 0x01, 0x00},
+		},
+		{
+			"non-speculative index 8191/8192 varint boundary, low side",
+			typex.PaneLate,
+			true,
+			true,
+			0,
+			8191,
+			0b00101011,
+			[]byte{0x2b, 0x00, 0xfe, 0x7f},
+		},
+		{
+			"non-speculative index 8191/8192 varint boundary, high side",
+			typex.PaneLate,
+			true,
+			true,
+			0,
+			8192,
+			0b00101011,
+			[]byte{0x2b, 0x00, 0x80, 0x80, 0x01},
+		},
+		{
+			"early pane, max index",
+			typex.PaneEarly,
+			true,
+			true,
+			math.MaxInt64,
+			-1,
+			0b00010011,
+			[]byte{0x13, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+		{
+			"early pane, min index",
+			typex.PaneEarly,
+			false,
+			false,
+			math.MinInt64,
+			-1,
+			0b00010000,
+			[]byte{0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
 		},
 	}
 	for _, test := range tests {
@@ -167,10 +287,12 @@ func TestPaneCoder(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to encode pane %v, got %v", input, err)
 			}
-			first := buf.Bytes()[0]
-			if got, want := first, test.firstByte; got != want {
+			if got, want := buf.Bytes()[0], test.firstByte; got != want {
 				t.Errorf("Unexpected First Byte: got %#08b, want %#08b, for %v ", got, want, input)
 			}
+			if got, want := buf.Bytes(), test.wire; !bytes.Equal(got, want) {
+				t.Errorf("Unexpected wire bytes: got %#v, want %#v, for %v", got, want, input)
+			}
 			got, err := DecodePane(&buf)
 			if err != nil {
 				t.Fatalf("failed to decode pane from buffer %v, got %v", &buf, err)
@@ -182,6 +304,97 @@ func TestPaneCoder(t *testing.T) {
 	}
 }
 
+// TestPaneCoderWireFormatFixture decodes and re-encodes a fixture of
+// hex-encoded panes covering the varint boundary and edge cases, as a
+// regression check that the wire format captured in testdata/panes_golden.csv
+// doesn't drift. The fixture values are derived from the same zigzag-varint
+// encoding as EncodePane/DecodePane, not from an external SDK, so this
+// complements but doesn't replace TestPaneCoder's wire-byte assertions.
+func TestPaneCoderWireFormatFixture(t *testing.T) {
+	f, err := os.Open("testdata/panes_golden.csv")
+	if err != nil {
+		t.Fatalf("failed to open golden fixture: %v", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse golden fixture: %v", err)
+	}
+
+	timings := map[string]typex.PaneTiming{
+		"PaneEarly":   typex.PaneEarly,
+		"PaneOnTime":  typex.PaneOnTime,
+		"PaneLate":    typex.PaneLate,
+		"PaneUnknown": typex.PaneUnknown,
+	}
+
+	for i, rec := range records {
+		rec := rec
+		t.Run(fmt.Sprintf("row%d/%s", i, rec[0]), func(t *testing.T) {
+			wire, err := parseHex(rec[0])
+			if err != nil {
+				t.Fatalf("bad hex in fixture row %v: %v", rec, err)
+			}
+			timing, ok := timings[rec[1]]
+			if !ok {
+				t.Fatalf("unknown timing %q in fixture row %v", rec[1], rec)
+			}
+			first, err := strconv.ParseBool(rec[2])
+			if err != nil {
+				t.Fatalf("bad isFirst in fixture row %v: %v", rec, err)
+			}
+			last, err := strconv.ParseBool(rec[3])
+			if err != nil {
+				t.Fatalf("bad isLast in fixture row %v: %v", rec, err)
+			}
+			index, err := strconv.ParseInt(rec[4], 10, 64)
+			if err != nil {
+				t.Fatalf("bad index in fixture row %v: %v", rec, err)
+			}
+			nsIndex, err := strconv.ParseInt(rec[5], 10, 64)
+			if err != nil {
+				t.Fatalf("bad nonSpeculativeIndex in fixture row %v: %v", rec, err)
+			}
+			want := makePaneInfo(timing, first, last, index, nsIndex)
+
+			got, err := DecodePane(bytes.NewReader(wire))
+			if err != nil {
+				t.Fatalf("failed to decode golden pane %x, got %v", wire, err)
+			}
+			if !equalPanes(got, want) {
+				t.Errorf("decoded golden pane %x: got %v, want %v", wire, got, want)
+			}
+
+			var buf bytes.Buffer
+			if err := EncodePane(want, &buf); err != nil {
+				t.Fatalf("failed to re-encode golden pane %v, got %v", want, err)
+			}
+			if !bytes.Equal(buf.Bytes(), wire) {
+				t.Errorf("re-encoded golden pane %v: got %x, want %x", want, buf.Bytes(), wire)
+			}
+		})
+	}
+}
+
+func parseHex(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("odd-length hex string %q", s)
+	}
+	out := make([]byte, len(s)/2)
+	for i := range out {
+		b, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = byte(b)
+	}
+	return out, nil
+}
+
 func TestEncodePane_bad(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -207,6 +420,22 @@ func TestEncodePane_bad(t *testing.T) {
 			math.MinInt64,
 			math.MinInt64,
 		},
+		{
+			"invalid unknown pane, nonzero index",
+			typex.PaneUnknown,
+			true,
+			true,
+			1,
+			0,
+		},
+		{
+			"invalid unknown pane, nonzero non-speculative index",
+			typex.PaneUnknown,
+			true,
+			true,
+			0,
+			1,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -219,3 +448,239 @@ func TestEncodePane_bad(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodePane_bad(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{
+			"empty buffer",
+			[]byte{},
+		},
+		{
+			"truncated after descriptor byte, on time pane expects two varints",
+			[]byte{0b00100100},
+		},
+		{
+			"truncated after partial index varint",
+			[]byte{0b00100100, 0x80},
+		},
+		{
+			"truncated after index, missing non-speculative index",
+			[]byte{0b00100100, 0x01},
+		},
+		{
+			"malformed varint exceeding 10 bytes",
+			[]byte{0b00100100, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodePane(bytes.NewReader(test.in)); err == nil {
+				t.Errorf("successfully decoded malformed buffer %x when it should have failed", test.in)
+			}
+		})
+	}
+}
+
+// onlyReader hides any io.ByteReader implementation a wrapped reader may
+// have, forcing DecodePane onto its bufio.NewReader fallback path.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+// TestDecodePane_plainReader exercises DecodePane's bufio.NewReader
+// fallback, used when r doesn't already implement io.ByteReader, with
+// multi-byte varints.
+func TestDecodePane_plainReader(t *testing.T) {
+	tests := []struct {
+		name string
+		pane typex.PaneInfo
+	}{
+		{"on time, multi-byte index and non-speculative index", makePaneInfo(typex.PaneOnTime, false, false, 128, 16384)},
+		{"late, max index and zero non-speculative index", makePaneInfo(typex.PaneLate, false, false, math.MaxInt64, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodePane(test.pane, &buf); err != nil {
+				t.Fatalf("failed to encode pane %v, got %v", test.pane, err)
+			}
+			got, err := DecodePane(onlyReader{&buf})
+			if err != nil {
+				t.Fatalf("failed to decode pane %v from a plain io.Reader, got %v", test.pane, err)
+			}
+			if want := test.pane; !equalPanes(got, want) {
+				t.Errorf("got pane %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// FuzzPaneCoder checks that every PaneInfo accepted by EncodePane round-trips
+// through DecodePane unchanged. Inputs that violate EncodePane's invariants
+// (an early pane with a non- -1 NonSpeculativeIndex, or an unknown-timing
+// pane with a nonzero Index or NonSpeculativeIndex, per TestEncodePane_bad)
+// are expected to be rejected and are skipped rather than treated as
+// failures.
+func FuzzPaneCoder(f *testing.F) {
+	tests := []struct {
+		timing  typex.PaneTiming
+		first   bool
+		last    bool
+		index   int64
+		nsIndex int64
+	}{
+		{typex.PaneUnknown, false, false, 0, 0},
+		{typex.PaneUnknown, true, true, 0, 0},
+		{typex.PaneUnknown, true, false, 0, 0},
+		{typex.PaneUnknown, false, true, 0, 0},
+		{typex.PaneOnTime, false, false, 1, 2},
+		{typex.PaneEarly, true, false, math.MaxInt64, -1},
+		{typex.PaneOnTime, false, true, 0, math.MaxInt64},
+		{typex.PaneLate, false, false, math.MaxInt64, 0},
+		{typex.PaneOnTime, false, true, 0, math.MinInt64},
+		{typex.PaneLate, false, false, math.MinInt64, 0},
+		{typex.PaneLate, false, true, 2, 1},
+		{typex.PaneLate, true, false, 2, 1},
+		{typex.PaneEarly, false, true, 0, -1},
+		{typex.PaneEarly, true, false, math.MaxInt64, math.MaxInt64}, // rejected
+		{typex.PaneEarly, true, false, math.MinInt64, math.MinInt64}, // rejected
+		{typex.PaneUnknown, true, true, 1, 0},                        // rejected
+		{typex.PaneUnknown, true, true, 0, 1},                        // rejected
+	}
+	for _, test := range tests {
+		f.Add(byte(test.timing), test.first, test.last, test.index, test.nsIndex)
+	}
+
+	f.Fuzz(func(t *testing.T, timing byte, first, last bool, index, nsIndex int64) {
+		input := makePaneInfo(typex.PaneTiming(timing%4), first, last, index, nsIndex)
+
+		var buf bytes.Buffer
+		err := EncodePane(input, &buf)
+		if err != nil {
+			// EncodePane legitimately rejects these combinations; see
+			// TestEncodePane_bad.
+			if input.Timing == typex.PaneEarly && input.NonSpeculativeIndex != -1 {
+				return
+			}
+			if input.Timing == typex.PaneUnknown && (input.Index != 0 || input.NonSpeculativeIndex != 0) {
+				return
+			}
+			t.Fatalf("failed to encode valid pane %v, got %v", input, err)
+		}
+
+		got, err := DecodePane(&buf)
+		if err != nil {
+			t.Fatalf("failed to decode pane encoded from %v, got %v", input, err)
+		}
+		if !equalPanes(got, input) {
+			t.Errorf("round-trip mismatch: got %v, want %v", got, input)
+		}
+	})
+}
+
+// FuzzDecodePane checks that DecodePane never panics on malformed input,
+// including empty buffers, truncated varints, and varints that exceed the
+// 10-byte limit for a 64-bit value.
+func FuzzDecodePane(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0b00001100})
+	f.Add([]byte{0b00100100})
+	f.Add([]byte{0b00100100, 0x80})
+	f.Add([]byte{0b00100100, 0x01})
+	f.Add([]byte{0b00100100, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80})
+	f.Add([]byte{0x11, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// DecodePane must either return a value or an error, never panic.
+		DecodePane(bytes.NewReader(data))
+	})
+}
+
+// These benchmarks measure the pane coder in isolation. This change doesn't
+// wire AppendPane/ConsumePane into a windowed-value caller: this checkout
+// contains only this coder package, not the exec package that would hold
+// such a caller, so there's nothing here to wire the fast path into or
+// benchmark end-to-end.
+var paneBenchCases = []struct {
+	name string
+	pane typex.PaneInfo
+}{
+	{"NoFiringPane", makePaneInfo(typex.PaneUnknown, true, true, 0, 0)},
+	{"OnTimeSingleFiring", makePaneInfo(typex.PaneOnTime, true, true, 0, 0)},
+	{"LateLargeIndices", makePaneInfo(typex.PaneLate, false, false, math.MaxInt64, math.MaxInt64)},
+}
+
+func BenchmarkEncodePane(b *testing.B) {
+	for _, bc := range paneBenchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := EncodePane(bc.pane, &buf); err != nil {
+					b.Fatalf("failed to encode pane %v, got %v", bc.pane, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodePane(b *testing.B) {
+	for _, bc := range paneBenchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			var buf bytes.Buffer
+			if err := EncodePane(bc.pane, &buf); err != nil {
+				b.Fatalf("failed to encode pane %v, got %v", bc.pane, err)
+			}
+			wire := buf.Bytes()
+			r := bytes.NewReader(wire)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Reset(wire)
+				if _, err := DecodePane(r); err != nil {
+					b.Fatalf("failed to decode pane %v, got %v", bc.pane, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppendPane(b *testing.B) {
+	for _, bc := range paneBenchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			dst := make([]byte, 0, MaxPaneEncodedSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var err error
+				dst, err = AppendPane(dst[:0], bc.pane)
+				if err != nil {
+					b.Fatalf("failed to append pane %v, got %v", bc.pane, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkConsumePane(b *testing.B) {
+	for _, bc := range paneBenchCases {
+		b.Run(bc.name, func(b *testing.B) {
+			wire, err := AppendPane(nil, bc.pane)
+			if err != nil {
+				b.Fatalf("failed to append pane %v, got %v", bc.pane, err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := ConsumePane(wire); err != nil {
+					b.Fatalf("failed to consume pane %v, got %v", bc.pane, err)
+				}
+			}
+		})
+	}
+}